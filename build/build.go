@@ -0,0 +1,128 @@
+// Package build implements the download-extract-makepkg pipeline used to
+// turn a chosen AUR package into an installed one.
+package build
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Install downloads the tarball at tarballURL, extracts it into a
+// per-package directory under buildDir, optionally opens the PKGBUILD in
+// editorBin for review, and runs makepkgBin -si (plus any extra pacman
+// flags) inside that directory. Output is streamed directly to the user's
+// terminal so makepkg's own prompts still work. editorBin is skipped
+// entirely when empty.
+func Install(name, tarballURL, buildDir, makepkgBin, editorBin string, pacmanArgs []string) error {
+	dir := filepath.Join(buildDir, name)
+	if err := fetchAndExtract(tarballURL, dir); err != nil {
+		return err
+	}
+
+	if editorBin != "" {
+		edit := exec.Command(editorBin, "PKGBUILD")
+		edit.Dir = dir
+		edit.Stdin = os.Stdin
+		edit.Stdout = os.Stdout
+		edit.Stderr = os.Stderr
+		if err := edit.Run(); err != nil {
+			return err
+		}
+	}
+
+	args := append([]string{"-si"}, pacmanArgs...)
+	cmd := exec.Command(makepkgBin, args...)
+	cmd.Dir = dir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// fetchAndExtract downloads the gzipped tarball at tarballURL and extracts
+// it into dir, which is created if necessary. The tarball's own top-level
+// directory (the pkgbase, which can differ from dir's pkgname for split
+// packages) is discarded and every entry is rehomed under dir instead, so
+// callers can always find the checkout at the name they asked for.
+func fetchAndExtract(tarballURL, dir string) error {
+	resp, err := http.Get(tarballURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	gzr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(gzr)
+	var rootPrefix string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			// git-archive tarballs open with a pax_global_header entry that
+			// is not part of the package's directory tree
+			continue
+		}
+
+		if rootPrefix == "" {
+			if idx := strings.IndexByte(hdr.Name, '/'); idx != -1 {
+				rootPrefix = hdr.Name[:idx]
+			} else {
+				rootPrefix = hdr.Name
+			}
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(hdr.Name, rootPrefix), "/")
+		if rel == "" {
+			continue
+		}
+		rel = filepath.Clean(rel)
+		if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+			return fmt.Errorf("tar entry %q escapes build directory", hdr.Name)
+		}
+
+		target := filepath.Join(dir, rel)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.FileMode(hdr.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			f.Close()
+		default:
+			return fmt.Errorf("unsupported tar entry type for %s", hdr.Name)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Package cache provides a simple TTL'd on-disk cache keyed by an
+// arbitrary string, used to avoid repeating identical AUR RPC requests
+// within a single seekaur session.
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache stores values as files under dir, named by the SHA-1 of their key.
+// A zero TTL disables caching entirely: Get always misses and Set is a
+// no-op, so callers don't need to special-case a disabled cache.
+type Cache struct {
+	dir string
+	ttl time.Duration
+}
+
+// New returns a Cache that stores entries under dir and considers them
+// stale after ttl.
+func New(dir string, ttl time.Duration) *Cache {
+	return &Cache{dir: dir, ttl: ttl}
+}
+
+func (c *Cache) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+// Get returns the cached value for key, if present and not older than the
+// cache's TTL.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > c.ttl {
+		return nil, false
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Set stores data under key, creating the cache directory if needed.
+func (c *Cache) Set(key string, data []byte) error {
+	if c.ttl <= 0 {
+		return nil
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path(key), data, 0644)
+}
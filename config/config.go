@@ -0,0 +1,118 @@
+// Package config loads seekaur's persistent, XDG-compliant configuration
+// file, writing out a default one on first run.
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Config holds every user-tunable setting seekaur has. Zero-value fields
+// left out of the config file on disk are filled in from Default when the
+// file is unmarshaled.
+type Config struct {
+	BuildDir       string `json:"build_dir"`
+	MakepkgBin     string `json:"makepkg_bin"`
+	PacmanBin      string `json:"pacman_bin"`
+	Editor         string `json:"editor"`
+	SortMode       string `json:"sort_mode"`
+	NoConfirm      bool   `json:"no_confirm"`
+	RequestTimeout int    `json:"request_timeout"` // seconds
+	AURURL         string `json:"aur_url"`
+	CacheTTL       int    `json:"cache_ttl"` // seconds; 0 disables the RPC response cache
+	Colors         Colors `json:"colors"`
+}
+
+// Colors holds the ANSI escape sequences used to format seekaur's output.
+// Setting a field to "" disables that particular styling.
+type Colors struct {
+	Bold      string `json:"bold"`      // field labels in `info`
+	Error     string `json:"error"`     // "error:" prefixes
+	Good      string `json:"good"`      // up to date versions, new versions in `upgrade`
+	Header    string `json:"header"`    // the "aur/" prefix in `search`
+	Highlight string `json:"highlight"` // package names
+	Prompt    string `json:"prompt"`    // numbered choices in `search --install`
+	Reset     string `json:"reset"`
+}
+
+// Default returns the configuration seekaur uses when no config file, or
+// an incomplete one, is found.
+func Default() Config {
+	return Config{
+		BuildDir:       "/tmp/seekaur",
+		MakepkgBin:     "makepkg",
+		PacmanBin:      "pacman",
+		Editor:         "",
+		SortMode:       "name",
+		NoConfirm:      false,
+		RequestTimeout: 30,
+		AURURL:         "https://aur.archlinux.org",
+		CacheTTL:       300,
+		Colors: Colors{
+			Bold:      "\x1B[1m",
+			Error:     "\x1B[1;31m",
+			Good:      "\x1B[1;32m",
+			Header:    "\x1B[1;35m",
+			Highlight: "\x1B[1;37m",
+			Prompt:    "\x1B[1;33m",
+			Reset:     "\x1B[0m",
+		},
+	}
+}
+
+// Dir returns $XDG_CONFIG_HOME/seekaur, falling back to ~/.config/seekaur.
+func Dir() string {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		base = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(base, "seekaur")
+}
+
+// Path returns the default config file location, Dir()/config.json.
+func Path() string {
+	return filepath.Join(Dir(), "config.json")
+}
+
+// CacheDir returns $XDG_CACHE_HOME/seekaur, falling back to
+// ~/.cache/seekaur.
+func CacheDir() string {
+	base := os.Getenv("XDG_CACHE_HOME")
+	if base == "" {
+		base = filepath.Join(os.Getenv("HOME"), ".cache")
+	}
+	return filepath.Join(base, "seekaur")
+}
+
+// Load reads the config file at path, merging it over Default(). If no
+// file exists yet, Default() is written out to path and returned, so a
+// fresh install ends up with an editable config on disk.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, write(path, cfg)
+	}
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func write(path string, cfg Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,129 @@
+// Package deps resolves the AUR dependency graph for a set of target
+// packages, classifying each dependency as either already satisfiable
+// from the pacman repos or requiring a recursive AUR build.
+package deps
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// AURInfo is the subset of an AUR package's dependency arrays that the
+// resolver needs. It is deliberately decoupled from the seekaur.Package
+// type so this package has no dependency on package main.
+type AURInfo struct {
+	Name         string
+	Depends      []string
+	MakeDepends  []string
+	CheckDepends []string
+}
+
+// Node is one package in the resolved dependency graph.
+type Node struct {
+	Name       string
+	Constraint string
+	IsRepo     bool
+	// MakeOnly is true if this package is only ever needed to build another
+	// package (via makedepends/checkdepends) and is never a runtime
+	// dependency, making it a candidate for removal after the build.
+	MakeOnly bool
+	Deps     []*Node
+}
+
+// ParseConstraint splits an AUR dependency string such as "foo>=1.2" into
+// the bare package name and its version constraint (eg ">=1.2"). Strings
+// with no constraint operator are returned with an empty constraint.
+func ParseConstraint(raw string) (name, constraint string) {
+	for _, op := range []string{">=", "<=", "==", "=", ">", "<"} {
+		if idx := strings.Index(raw, op); idx != -1 {
+			return raw[:idx], raw[idx:]
+		}
+	}
+	return raw, ""
+}
+
+// IsRepoPackage reports whether name is satisfiable from a pacman sync
+// repo (as opposed to needing to come from the AUR), by shelling out to
+// `<pacmanBin> -Si`.
+func IsRepoPackage(pacmanBin, name string) bool {
+	return exec.Command(pacmanBin, "-Si", name).Run() == nil
+}
+
+// Resolve walks the dependency graph rooted at the given package names.
+// fetch is called with a batch of AUR package names not yet known to be
+// repo packages, and must return AURInfo for each one found; isRepo
+// classifies a name as satisfiable from the pacman repos. Resolve returns
+// a build order with leaf dependencies first, or an error if the graph
+// contains a cycle.
+func Resolve(roots []string, isRepo func(string) bool, fetch func([]string) (map[string]AURInfo, error)) ([]*Node, error) {
+	nodes := map[string]*Node{}
+	const (
+		white = iota
+		gray
+		black
+	)
+	color := map[string]int{}
+	var order []*Node
+
+	var visit func(name, constraint string, makeOnly bool) error
+	visit = func(name, constraint string, makeOnly bool) error {
+		switch color[name] {
+		case gray:
+			return fmt.Errorf("dependency cycle detected at package '%s'", name)
+		case black:
+			if n, ok := nodes[name]; ok && !makeOnly {
+				n.MakeOnly = false
+			}
+			return nil
+		}
+		color[name] = gray
+
+		node := &Node{Name: name, Constraint: constraint, MakeOnly: makeOnly}
+		if isRepo(name) {
+			node.IsRepo = true
+			nodes[name] = node
+			color[name] = black
+			order = append(order, node)
+			return nil
+		}
+
+		infos, err := fetch([]string{name})
+		if err != nil {
+			return err
+		}
+		info, ok := infos[name]
+		if !ok {
+			return fmt.Errorf("package '%s' was not found in the AUR or any repo", name)
+		}
+
+		nodes[name] = node
+		for _, raw := range info.Depends {
+			depName, depConstraint := ParseConstraint(raw)
+			if err := visit(depName, depConstraint, false); err != nil {
+				return err
+			}
+			node.Deps = append(node.Deps, nodes[depName])
+		}
+		for _, raw := range append(append([]string{}, info.MakeDepends...), info.CheckDepends...) {
+			depName, depConstraint := ParseConstraint(raw)
+			if err := visit(depName, depConstraint, true); err != nil {
+				return err
+			}
+			node.Deps = append(node.Deps, nodes[depName])
+		}
+
+		color[name] = black
+		order = append(order, node)
+		return nil
+	}
+
+	for _, root := range roots {
+		name, constraint := ParseConstraint(root)
+		if err := visit(name, constraint, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
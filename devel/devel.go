@@ -0,0 +1,103 @@
+// Package devel tracks the upstream revision of installed VCS (-git,
+// -svn, -hg, -bzr) AUR packages, so that seekaur can report them as
+// upgradable even when their AUR Version string hasn't changed.
+package devel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/tummychow/seekaur/pkgbuild"
+)
+
+// Cache is the on-disk record of the last-seen upstream revision for each
+// devel package, keyed by package name.
+type Cache struct {
+	path      string
+	Revisions map[string]string `json:"revisions"`
+}
+
+// Path returns the default location of the cache file, honoring
+// XDG_CACHE_HOME.
+func Path() string {
+	base := os.Getenv("XDG_CACHE_HOME")
+	if base == "" {
+		base = filepath.Join(os.Getenv("HOME"), ".cache")
+	}
+	return filepath.Join(base, "seekaur", "vcs.json")
+}
+
+// Load reads the cache from path, returning an empty cache if it doesn't
+// exist yet.
+func Load(path string) (*Cache, error) {
+	c := &Cache{path: path, Revisions: map[string]string{}}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Save writes the cache back to its path, creating parent directories as
+// needed.
+func (c *Cache) Save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, data, 0644)
+}
+
+// LatestRevision resolves src's fragment (branch/tag/commit) against the
+// live upstream repository and returns the current revision.
+func LatestRevision(src *pkgbuild.VCSSource) (string, error) {
+	switch src.Kind {
+	case "git":
+		return latestGitRevision(src.URL, src.Fragment)
+	default:
+		// svn/hg/bzr upstream checks aren't implemented yet; every other
+		// piece of the pipeline (parsing, caching) already supports them.
+		return "", fmt.Errorf("devel-check does not yet support %s sources", src.Kind)
+	}
+}
+
+func latestGitRevision(url, fragment string) (string, error) {
+	ref := "HEAD"
+	for _, key := range []string{"branch=", "tag=", "commit="} {
+		if !strings.HasPrefix(fragment, key) {
+			continue
+		}
+		val := strings.TrimPrefix(fragment, key)
+		if key == "commit=" {
+			// pinned to a specific commit, which by definition never advances
+			return val, nil
+		}
+		ref = val
+	}
+
+	out, err := exec.Command("git", "ls-remote", url, ref).Output()
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("git ls-remote returned nothing for %s", url)
+	}
+	return fields[0], nil
+}
@@ -0,0 +1,115 @@
+// Package pkgbuild extracts the handful of PKGBUILD variables seekaur
+// needs (pkgname, pkgbase, source) without shelling out to bash. It does
+// not evaluate the script, so values built up from other variables or
+// command substitution are not supported.
+package pkgbuild
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ParseString extracts a scalar assignment like `pkgbase=foo` from a
+// PKGBUILD, stripping a single layer of surrounding quotes if present.
+func ParseString(script, name string) (string, error) {
+	re := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(name) + `\s*=\s*(.+)$`)
+	m := re.FindStringSubmatch(script)
+	if m == nil {
+		return "", fmt.Errorf("no %s assignment found in PKGBUILD", name)
+	}
+	val := strings.TrimSpace(m[1])
+	if len(val) >= 2 && (val[0] == '\'' || val[0] == '"') && val[len(val)-1] == val[0] {
+		val = val[1 : len(val)-1]
+	}
+	return val, nil
+}
+
+// ParseArray extracts a bash array assignment like `source=('a' "b" c)`
+// from a PKGBUILD, returning its elements with quoting removed. Arrays
+// spanning multiple lines are supported; nested parentheses are not.
+func ParseArray(script, name string) ([]string, error) {
+	re := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(name) + `\s*=\s*\(`)
+	loc := re.FindStringIndex(script)
+	if loc == nil {
+		return nil, fmt.Errorf("no %s array found in PKGBUILD", name)
+	}
+
+	rest := script[loc[1]:]
+	end := strings.Index(rest, ")")
+	if end == -1 {
+		return nil, fmt.Errorf("unterminated %s array in PKGBUILD", name)
+	}
+
+	return tokenizeQuoted(rest[:end]), nil
+}
+
+// tokenizeQuoted splits s on unquoted whitespace, stripping a single layer
+// of single or double quotes from each resulting token.
+func tokenizeQuoted(s string) []string {
+	var tokens []string
+	var cur strings.Builder
+	var quote rune
+
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// VCSSource is a parsed VCS source=() entry, eg
+// "git+https://example.com/repo.git#branch=master".
+type VCSSource struct {
+	Kind     string // git, svn, hg or bzr
+	URL      string
+	Fragment string // eg "branch=master", "tag=v1.0" or "commit=abcdef"
+}
+
+var vcsKinds = []string{"git", "svn", "hg", "bzr"}
+
+// ParseVCSSource parses a source=() entry, stripping any "name::" alias
+// prefix, and returns an error if it does not use one of the recognized
+// "<kind>+<url>" VCS forms.
+func ParseVCSSource(raw string) (*VCSSource, error) {
+	if idx := strings.Index(raw, "::"); idx != -1 {
+		raw = raw[idx+2:]
+	}
+
+	for _, kind := range vcsKinds {
+		prefix := kind + "+"
+		if !strings.HasPrefix(raw, prefix) {
+			continue
+		}
+
+		rest := raw[len(prefix):]
+		url, fragment := rest, ""
+		if idx := strings.Index(rest, "#"); idx != -1 {
+			url, fragment = rest[:idx], rest[idx+1:]
+		}
+		return &VCSSource{Kind: kind, URL: url, Fragment: fragment}, nil
+	}
+
+	return nil, fmt.Errorf("'%s' is not a recognized VCS source", raw)
+}
@@ -1,34 +1,62 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/tummychow/seekaur/build"
+	"github.com/tummychow/seekaur/cache"
+	"github.com/tummychow/seekaur/config"
+	"github.com/tummychow/seekaur/deps"
+	"github.com/tummychow/seekaur/devel"
+	"github.com/tummychow/seekaur/pkgbuild"
+	"github.com/tummychow/seekaur/vercmp"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
-var aurURL, _ = url.Parse("https://aur.archlinux.org")
+// aurURL, cfg, httpClient and respCache are all populated by the root
+// command's PersistentPreRunE, once the effective config has been loaded.
+var aurURL *url.URL
+var cfg config.Config
+var httpClient = &http.Client{}
+var respCache *cache.Cache
 
 type Package struct {
-	Maintainer     string
-	ID             int
-	Name           string
-	Version        string
-	CategoryID     int
-	Description    string
-	URL            string
-	License        string
-	NumVotes       int
-	OutOfDate      int // actually a boolean but the JSON response is 0/1
-	FirstSubmitted timeUnmarshaler
-	LastModified   timeUnmarshaler
-	URLPath        string
+	Maintainer     string          `json:"maintainer"`
+	ID             int             `json:"id"`
+	Name           string          `json:"name"`
+	PackageBaseID  int             `json:"packagebaseid"`
+	PackageBase    string          `json:"packagebase"`
+	Version        string          `json:"version"`
+	Description    string          `json:"description"`
+	URL            string          `json:"url"`
+	License        string          `json:"license"`
+	NumVotes       int             `json:"numvotes"`
+	Popularity     float64         `json:"popularity"`
+	OutOfDate      int             `json:"outofdate"` // actually a boolean but the JSON response is 0/1
+	FirstSubmitted timeUnmarshaler `json:"firstsubmitted"`
+	LastModified   timeUnmarshaler `json:"lastmodified"`
+	URLPath        string          `json:"urlpath"`
+	Depends        []string        `json:"depends"`
+	MakeDepends    []string        `json:"makedepends"`
+	CheckDepends   []string        `json:"checkdepends"`
+	OptDepends     []string        `json:"optdepends"`
+	Conflicts      []string        `json:"conflicts"`
+	Provides       []string        `json:"provides"`
+	Replaces       []string        `json:"replaces"`
+	Groups         []string        `json:"groups"`
+	Keywords       []string        `json:"keywords"`
 }
 
 type PackageList []Package
@@ -36,23 +64,28 @@ type PackageList []Package
 func (p PackageList) Len() int      { return len(p) }
 func (p PackageList) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
 
-// implements the same sorting order as https://aur.archlinux.org/packages/
+// sortMode controls PackageList's ordering, and is set from the --sort
+// persistent flag.
+var sortMode = "name"
+
+// Less orders by sortMode: "popularity" and "votes" sort highest first,
+// and "name" (the default) sorts alphabetically.
 func (p PackageList) Less(i, j int) bool {
-	if p[i].CategoryID < p[j].CategoryID {
-		// sort by category
-		return true
-	}
-	if p[i].CategoryID == p[j].CategoryID && p[i].Name < p[j].Name {
-		// items in same category get sorted by name
-		return true
+	switch sortMode {
+	case "popularity":
+		return p[i].Popularity > p[j].Popularity
+	case "votes":
+		return p[i].NumVotes > p[j].NumVotes
+	default:
+		return p[i].Name < p[j].Name
 	}
-	return false
 }
 
 type Response struct {
-	Type    string
-	Count   int
-	Results []Package
+	Type        string    `json:"type"`
+	ResultCount int       `json:"resultcount"`
+	Results     []Package `json:"results"`
+	Error       string    `json:"error"`
 }
 
 // unmarshals time.Time in the Unix format instead of RFC3339
@@ -70,21 +103,40 @@ func (t *timeUnmarshaler) UnmarshalJSON(str []byte) error {
 
 // aurRequest takes a string representing an RPC request to the AUR, and
 // returns a Response containing the results of the request. The string must
-// already be escaped where desired, eg "/rpc.php?type=search&arg=jquery".
+// already be escaped where desired, eg "/rpc/?v=5&type=search&arg=jquery".
+// Responses are served from respCache when possible, to avoid repeating
+// identical requests within a session.
 func aurRequest(request string) (r Response, err error) {
 	requestURL, err := url.Parse(request)
 	if err != nil {
 		return
 	}
+	fullURL := aurURL.ResolveReference(requestURL).String()
 
-	resp, err := http.Get(aurURL.ResolveReference(requestURL).String())
+	if respCache != nil {
+		if data, ok := respCache.Get(fullURL); ok {
+			err = json.Unmarshal(data, &r)
+			return
+		}
+	}
+
+	resp, err := httpClient.Get(fullURL)
 	if err != nil {
 		return
 	}
 	defer resp.Body.Close()
 
-	decoder := json.NewDecoder(resp.Body)
-	err = decoder.Decode(&r)
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &r); err != nil {
+		return
+	}
+
+	if respCache != nil {
+		respCache.Set(fullURL, data)
+	}
 	return
 }
 
@@ -97,7 +149,7 @@ func aurRequest(request string) (r Response, err error) {
 // return nil. If one or more of the listed packages did not exist, multiInfo
 // returns the error "Some packages were not found".
 func multiInfo(args []string, f func(Package) error) error {
-	request := "/rpc.php?type=multiinfo"
+	request := "/rpc/?v=5&type=info"
 	for _, str := range args {
 		request += "&arg[]="
 		request += url.QueryEscape(str)
@@ -124,7 +176,7 @@ func multiInfo(args []string, f func(Package) error) error {
 			}
 		}
 		if !found {
-			fmt.Printf("\x1B[1;31merror:\x1B[0m package '%s' was not found\n", args[arg])
+			fmt.Printf("%serror:%s package '%s' was not found\n", cfg.Colors.Error, cfg.Colors.Reset, args[arg])
 		}
 	}
 
@@ -134,61 +186,333 @@ func multiInfo(args []string, f func(Package) error) error {
 	return nil
 }
 
-func main() {
-	categories := []string{
-		1:  "none",
-		2:  "daemons",
-		3:  "devel",
-		4:  "editors",
-		5:  "emulators",
-		6:  "games",
-		7:  "gnome",
-		8:  "18n",
-		9:  "kde",
-		10: "lib",
-		11: "modules",
-		12: "multimedia",
-		13: "network",
-		14: "office",
-		15: "science",
-		16: "system",
-		17: "x11",
-		18: "xfce",
-		19: "kernels",
-		20: "fonts",
+// maxBatchSize bounds how many package names go into a single multiinfo
+// request, to stay well under the AUR's URL-length limit.
+const maxBatchSize = 150
+
+// chunkStrings splits items into consecutive slices of at most size
+// elements each.
+func chunkStrings(items []string, size int) [][]string {
+	var chunks [][]string
+	for len(items) > 0 {
+		if len(items) < size {
+			size = len(items)
+		}
+		chunks = append(chunks, items[:size])
+		items = items[size:]
+	}
+	return chunks
+}
+
+// installedForeignPackages returns the name and installed version of every
+// package pacman considers "foreign" (installed but absent from the sync
+// repos), which for most users is exactly their AUR-installed packages.
+func installedForeignPackages(pacmanBin string) (map[string]string, error) {
+	out, err := exec.Command(pacmanBin, "-Qm").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	installed := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		installed[fields[0]] = fields[1]
+	}
+	return installed, nil
+}
+
+// fetchAURVersions looks up the given package names in the AUR, batching
+// requests to respect the RPC's URL-length limit. Names with no matching
+// AUR package are silently omitted from the result, since a foreign
+// package may come from somewhere other than the AUR.
+func fetchAURVersions(names []string) (map[string]Package, error) {
+	found := map[string]Package{}
+	for _, batch := range chunkStrings(names, maxBatchSize) {
+		request := "/rpc/?v=5&type=info"
+		for _, name := range batch {
+			request += "&arg[]=" + url.QueryEscape(name)
+		}
+
+		resp, err := aurRequest(request)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range resp.Results {
+			found[p.Name] = p
+		}
+	}
+	return found, nil
+}
+
+// develSuffixes are the pkgname suffixes that mark a package as building
+// from a VCS checkout rather than a release tarball.
+var develSuffixes = []string{"-git", "-svn", "-hg", "-bzr"}
+
+func isDevelPackage(name string) bool {
+	for _, suffix := range develSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchPKGBUILD retrieves the raw PKGBUILD text for the given package base
+// from the AUR's cgit instance.
+func fetchPKGBUILD(pkgbase string) (string, error) {
+	resp, err := http.Get(aurURL.ResolveReference(mustParseURL("/cgit/aur.git/plain/PKGBUILD?h=" + pkgbase)).String())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// checkDevelPackages filters names down to -git/-svn/-hg/-bzr AUR
+// packages (by name suffix only; whether they are installed is not checked), resolves each one's live upstream revision, and returns
+// the ones whose revision has advanced since the last one recorded in
+// develCache, mapped to that newly observed revision. develCache itself
+// is left untouched: callers should only record a revision once the
+// corresponding package has actually been rebuilt, or a devel package
+// that's checked but never rebuilt would stop being reported as stale.
+func checkDevelPackages(develCache *devel.Cache, names []string) map[string]string {
+	stale := map[string]string{}
+	for _, name := range names {
+		if !isDevelPackage(name) {
+			continue
+		}
+
+		aurPkgs, err := fetchAURVersions([]string{name})
+		if err != nil {
+			fmt.Printf("%serror:%s %s: %s\n", cfg.Colors.Error, cfg.Colors.Reset, name, err)
+			continue
+		}
+		info, ok := aurPkgs[name]
+		if !ok {
+			continue
+		}
+
+		pkgb, err := fetchPKGBUILD(info.PackageBase)
+		if err != nil {
+			fmt.Printf("%serror:%s %s: %s\n", cfg.Colors.Error, cfg.Colors.Reset, name, err)
+			continue
+		}
+
+		sources, err := pkgbuild.ParseArray(pkgb, "source")
+		if err != nil || len(sources) == 0 {
+			continue
+		}
+		var src *pkgbuild.VCSSource
+		for _, raw := range sources {
+			if parsed, err := pkgbuild.ParseVCSSource(raw); err == nil {
+				src = parsed
+				break
+			}
+		}
+		if src == nil {
+			// pkgname looked like a devel package but none of its sources
+			// are actually a VCS URL - nothing we can check
+			continue
+		}
+
+		rev, err := devel.LatestRevision(src)
+		if err != nil {
+			fmt.Printf("%serror:%s %s: %s\n", cfg.Colors.Error, cfg.Colors.Reset, name, err)
+			continue
+		}
+
+		if develCache.Revisions[name] != rev {
+			stale[name] = rev
+		}
+	}
+	return stale
+}
+
+// promptAndInstall numbers the given packages (in bottomUp order if
+// requested, so the package nearest the prompt is index 1, matching
+// yaourt/yay), reads a whitespace-separated list of chosen indices from
+// stdin, and installs each one via the build package.
+func promptAndInstall(results []Package, bottomUp bool, pacmanArgs []string) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	ordered := make([]Package, len(results))
+	copy(ordered, results)
+	if bottomUp {
+		for i, j := 0, len(ordered)-1; i < j; i, j = i+1, j-1 {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		}
+	}
+
+	for k := range ordered {
+		// bottomUp prints the highest index first, so index 1 lands on the
+		// line just above the prompt
+		i := k
+		if bottomUp {
+			i = len(ordered) - 1 - k
+		}
+		fmt.Printf("%s%2d%s %s\n", cfg.Colors.Prompt, i+1, cfg.Colors.Reset, ordered[i].Name)
+	}
+
+	fmt.Print("==> Packages to install (eg: 1 2 3, leave blank for none): ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	// urlPaths accumulates the tarball URLPath for every package we learn
+	// about, whether it was in the original search results or discovered
+	// as a dependency below.
+	urlPaths := map[string]string{}
+	var chosen []string
+	for _, field := range strings.Fields(line) {
+		idx, err := strconv.Atoi(field)
+		if err != nil || idx < 1 || idx > len(ordered) {
+			fmt.Printf("%serror:%s invalid selection '%s'\n", cfg.Colors.Error, cfg.Colors.Reset, field)
+			continue
+		}
+
+		pkg := ordered[idx-1]
+		urlPaths[pkg.Name] = pkg.URLPath
+		chosen = append(chosen, pkg.Name)
+	}
+
+	if len(chosen) == 0 {
+		return nil
 	}
 
+	return resolveAndBuild(chosen, urlPaths, pacmanArgs, nil)
+}
+
+// resolveAndBuild resolves the AUR dependency tree for names (whose tarball
+// URLPaths are already known via urlPaths, with any newly-discovered
+// dependency's URLPath added as it's fetched), then builds and installs
+// each non-repo package in dependency order. onInstalled, if non-nil, is
+// called with each package's name right after it's successfully built.
+func resolveAndBuild(names []string, urlPaths map[string]string, pacmanArgs []string, onInstalled func(name string)) error {
+	fetch := func(names []string) (map[string]deps.AURInfo, error) {
+		infos := map[string]deps.AURInfo{}
+		err := multiInfo(names, func(p Package) error {
+			urlPaths[p.Name] = p.URLPath
+			infos[p.Name] = deps.AURInfo{
+				Name:         p.Name,
+				Depends:      p.Depends,
+				MakeDepends:  p.MakeDepends,
+				CheckDepends: p.CheckDepends,
+			}
+			return nil
+		})
+		return infos, err
+	}
+
+	isRepo := func(name string) bool { return deps.IsRepoPackage(cfg.PacmanBin, name) }
+	order, err := deps.Resolve(names, isRepo, fetch)
+	if err != nil {
+		return err
+	}
+
+	if cfg.NoConfirm {
+		pacmanArgs = append([]string{"--noconfirm"}, pacmanArgs...)
+	}
+
+	var makeOnly []string
+	for _, n := range order {
+		if n.IsRepo {
+			continue
+		}
+
+		tarballURL := aurURL.ResolveReference(mustParseURL(urlPaths[n.Name])).String()
+		if err := build.Install(n.Name, tarballURL, cfg.BuildDir, cfg.MakepkgBin, cfg.Editor, pacmanArgs); err != nil {
+			fmt.Printf("%serror:%s failed to install '%s': %s\n", cfg.Colors.Error, cfg.Colors.Reset, n.Name, err)
+			continue
+		}
+		if onInstalled != nil {
+			onInstalled(n.Name)
+		}
+		if n.MakeOnly {
+			makeOnly = append(makeOnly, n.Name)
+		}
+	}
+
+	if len(makeOnly) > 0 {
+		fmt.Printf("==> make dependencies that may now be removed: %s\n", strings.Join(makeOnly, " "))
+	}
+
+	return nil
+}
+
+func mustParseURL(ref string) *url.URL {
+	u, err := url.Parse(ref)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func main() {
+	var install bool
+	var bottomUp bool
 	var search = &cobra.Command{
-		Use:   "search [string to search]",
+		Use:   "search [string to search] [-- pacman flags]",
 		Short: "Search for packages whose name contains the argument",
-		Long:  `Displays the list of packages whose names contain the argument.`,
+		Long: `Displays the list of packages whose names contain the argument. With
+--install, the results are numbered and a whitespace-separated list of
+indices is read from stdin; the chosen packages are then downloaded and
+built with makepkg. Any flags given after "--" are passed through to
+pacman.`,
 		Run: func(cmd *cobra.Command, args []string) {
+			dash := cmd.ArgsLenAtDash()
+			pacmanArgs := []string{}
+			if dash >= 0 {
+				pacmanArgs = args[dash:]
+				args = args[:dash]
+			}
+
 			if len(args) != 1 {
 				println("search must be invoked with exactly one argument")
 				os.Exit(1)
 			}
 
-			results, err := aurRequest("/rpc.php?type=search&arg=" + url.QueryEscape(args[0]))
+			results, err := aurRequest("/rpc/?v=5&type=search&arg=" + url.QueryEscape(args[0]))
 			if err != nil {
 				panic(err)
 			}
 
 			sort.Sort(PackageList(results.Results))
 			for _, p := range results.Results {
-				fmt.Printf("%saur/%s/%s%s ", "\x1B[1;35m", categories[p.CategoryID], "\x1B[1;37m", p.Name)
+				fmt.Printf("%saur/%s%s ", cfg.Colors.Header, cfg.Colors.Highlight, p.Name)
 
 				if p.OutOfDate == 0 {
-					// the package is up to date
-					fmt.Print("\x1B[1;32m") // green
+					fmt.Print(cfg.Colors.Good)
 				} else {
-					fmt.Print("\x1B[1;31m") // red
+					fmt.Print(cfg.Colors.Error)
 				}
 				fmt.Println(p.Version)
 
-				fmt.Printf("    %s%s\n", "\x1B[0m", p.Description)
+				fmt.Printf("    %s%s\n", cfg.Colors.Reset, p.Description)
+			}
+
+			if install {
+				if err := promptAndInstall(results.Results, bottomUp, pacmanArgs); err != nil {
+					os.Exit(1)
+				}
 			}
 		},
 	}
+	search.Flags().BoolVarP(&install, "install", "i", false, "prompt for packages to install from the results")
+	search.Flags().BoolVar(&bottomUp, "bottomup", false, "number results bottom-up, like yaourt/yay")
 
 	var info = &cobra.Command{
 		Use:   "info [names of packages]",
@@ -203,20 +527,19 @@ cause an error to be displayed.`,
 			}
 
 			err := multiInfo(args, func(thispkg Package) error {
-				fmt.Printf("\x1B[1mCategory        : \x1B[0m%s\n", categories[thispkg.CategoryID])
-				fmt.Printf("\x1B[1mName            : \x1B[0m%s\n", thispkg.Name)
-				fmt.Printf("\x1B[1mVersion         : \x1B[0m%s", thispkg.Version)
+				fmt.Printf("%sName            : %s%s\n", cfg.Colors.Bold, cfg.Colors.Reset, thispkg.Name)
+				fmt.Printf("%sVersion         : %s%s", cfg.Colors.Bold, cfg.Colors.Reset, thispkg.Version)
 				if thispkg.OutOfDate != 0 {
 					fmt.Print(" [out of date]")
 				}
 				fmt.Println()
-				fmt.Printf("\x1B[1mDescription     : \x1B[0m%s\n", thispkg.Description)
-				fmt.Printf("\x1B[1mURL             : \x1B[0m%s\n", thispkg.URL)
-				fmt.Printf("\x1B[1mLicenses        : \x1B[0m%s\n", thispkg.License)
-				fmt.Printf("\x1B[1mMaintainer      : \x1B[0m%s\n", thispkg.Maintainer)
-				fmt.Printf("\x1B[1mFirst Submitted : \x1B[0m%s\n", thispkg.FirstSubmitted.Format("Mon 02 Jan 2006 03:04:05 PM MST"))
-				fmt.Printf("\x1B[1mLast Modified   : \x1B[0m%s\n", thispkg.LastModified.Format("Mon 02 Jan 2006 03:04:05 PM MST"))
-				fmt.Printf("\x1B[1mVotes           : \x1B[0m%v\n", thispkg.NumVotes)
+				fmt.Printf("%sDescription     : %s%s\n", cfg.Colors.Bold, cfg.Colors.Reset, thispkg.Description)
+				fmt.Printf("%sURL             : %s%s\n", cfg.Colors.Bold, cfg.Colors.Reset, thispkg.URL)
+				fmt.Printf("%sLicenses        : %s%s\n", cfg.Colors.Bold, cfg.Colors.Reset, thispkg.License)
+				fmt.Printf("%sMaintainer      : %s%s\n", cfg.Colors.Bold, cfg.Colors.Reset, thispkg.Maintainer)
+				fmt.Printf("%sFirst Submitted : %s%s\n", cfg.Colors.Bold, cfg.Colors.Reset, thispkg.FirstSubmitted.Format("Mon 02 Jan 2006 03:04:05 PM MST"))
+				fmt.Printf("%sLast Modified   : %s%s\n", cfg.Colors.Bold, cfg.Colors.Reset, thispkg.LastModified.Format("Mon 02 Jan 2006 03:04:05 PM MST"))
+				fmt.Printf("%sVotes           : %s%v\n", cfg.Colors.Bold, cfg.Colors.Reset, thispkg.NumVotes)
 				fmt.Println()
 				return nil
 			})
@@ -239,16 +562,12 @@ error to be displayed.`,
 				os.Exit(1)
 			}
 
-			//err := multiInfo(args, func(thispkg Package) error {
-			//	fmt.Printf("https://aur.archlinux.org%s\n", thispkg.URLPath)
-			//	return nil
-			//})
-			//if err != nil {
-			//	os.Exit(1)
-			//}
-
-			for _, s := range args {
-				fmt.Printf("https://aur.archlinux.org/packages/%s/%s/%s.tar.gz\n", s[0:2], s, s)
+			err := multiInfo(args, func(thispkg Package) error {
+				fmt.Println(aurURL.ResolveReference(mustParseURL(thispkg.URLPath)).String())
+				return nil
+			})
+			if err != nil {
+				os.Exit(1)
 			}
 		},
 	}
@@ -280,26 +599,229 @@ be displayed.`,
 				return nil
 			}
 
-			//err := multiInfo(args, func(thispkg Package) error {
-			//	aurURL, _ := url.Parse("https://aur.archlinux.org")
-			//	requestURL, err := url.Parse(thispkg.URLPath + "/../PKGBUILD")
-			//	if err != nil {
-			//		return err
-			//	}
-			//	return printPKGBUILD(aurURL.ResolveReference(requestURL).String())
-			//})
-			//if err != nil {
-			//	os.Exit(1)
-			//}
-
-			for _, s := range args {
-				err := printPKGBUILD("https://aur.archlinux.org/packages/" + s[0:2] + "/" + s + "/PKGBUILD")
+			err := multiInfo(args, func(thispkg Package) error {
+				pkgbuildURL := aurURL.ResolveReference(mustParseURL("/cgit/aur.git/plain/PKGBUILD?h=" + thispkg.PackageBase)).String()
+				return printPKGBUILD(pkgbuildURL)
+			})
+			if err != nil {
+				os.Exit(1)
+			}
+		},
+	}
+
+	var depscmd = &cobra.Command{
+		Use:   "deps [names of packages]",
+		Short: "Resolve and print the AUR dependency tree for the given packages",
+		Long: `Recursively resolves the depends, makedepends and checkdepends of each named
+package, classifying each dependency as either satisfiable from a pacman
+repo or requiring an AUR build, and prints the result in the order it
+would need to be built in (leaf dependencies first). Dependencies that
+are only needed to build another package, and never at runtime, are
+marked as make-only.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				println("deps requires at least one argument")
+				os.Exit(1)
+			}
+
+			fetch := func(names []string) (map[string]deps.AURInfo, error) {
+				result := map[string]deps.AURInfo{}
+				err := multiInfo(names, func(p Package) error {
+					result[p.Name] = deps.AURInfo{
+						Name:         p.Name,
+						Depends:      p.Depends,
+						MakeDepends:  p.MakeDepends,
+						CheckDepends: p.CheckDepends,
+					}
+					return nil
+				})
+				return result, err
+			}
+
+			isRepo := func(name string) bool { return deps.IsRepoPackage(cfg.PacmanBin, name) }
+			order, err := deps.Resolve(args, isRepo, fetch)
+			if err != nil {
+				fmt.Printf("%serror:%s %s\n", cfg.Colors.Error, cfg.Colors.Reset, err)
+				os.Exit(1)
+			}
+
+			for _, n := range order {
+				tag := "aur"
+				if n.IsRepo {
+					tag = "repo"
+				}
+				if n.MakeOnly {
+					tag += ", make-only"
+				}
+				fmt.Printf("%s%s (%s)\n", n.Name, n.Constraint, tag)
+			}
+		},
+	}
+
+	var upgradeYes bool
+	var upgradeDevel bool
+	var upgrade = &cobra.Command{
+		Use:   "upgrade [-- pacman flags]",
+		Short: "List foreign packages with a newer version available in the AUR",
+		Long: `Compares the version of every foreign package (as reported by pacman -Qm)
+against its current version in the AUR, and prints the ones that are out
+of date. With --devel, installed -git/-svn/-hg/-bzr packages whose
+upstream has advanced are also included, even though their AUR Version
+string is unchanged. With -y, packages needing an upgrade are downloaded,
+built and installed immediately instead of just being listed. Any flags
+given after "--" are passed through to pacman.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			dash := cmd.ArgsLenAtDash()
+			pacmanArgs := []string{}
+			if dash >= 0 {
+				pacmanArgs = args[dash:]
+			}
+
+			installed, err := installedForeignPackages(cfg.PacmanBin)
+			if err != nil {
+				panic(err)
+			}
+
+			names := make([]string, 0, len(installed))
+			for name := range installed {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			aurPkgs, err := fetchAURVersions(names)
+			if err != nil {
+				panic(err)
+			}
+
+			urlPaths := map[string]string{}
+			var outdated []string
+			for _, name := range names {
+				pkg, ok := aurPkgs[name]
+				if !ok {
+					// not an AUR package (or the AUR doesn't know it anymore)
+					continue
+				}
+
+				cmp, err := vercmp.Compare(installed[name], pkg.Version)
+				if err != nil {
+					panic(err)
+				}
+				if cmp >= 0 {
+					continue
+				}
+
+				fmt.Printf("%s%s %s%s->%s%s%s\n", cfg.Colors.Highlight, name, installed[name], cfg.Colors.Error, cfg.Colors.Good, pkg.Version, cfg.Colors.Reset)
+				urlPaths[name] = pkg.URLPath
+				outdated = append(outdated, name)
+			}
+
+			var develCache *devel.Cache
+			staleRevs := map[string]string{}
+			if upgradeDevel {
+				alreadyOutdated := map[string]bool{}
+				for _, name := range outdated {
+					alreadyOutdated[name] = true
+				}
+
+				var err error
+				develCache, err = devel.Load(devel.Path())
+				if err != nil {
+					panic(err)
+				}
+
+				stale := checkDevelPackages(develCache, names)
+				staleNames := make([]string, 0, len(stale))
+				for name := range stale {
+					staleNames = append(staleNames, name)
+				}
+				sort.Strings(staleNames)
+
+				for _, name := range staleNames {
+					if alreadyOutdated[name] {
+						continue
+					}
+					if pkg, ok := aurPkgs[name]; ok {
+						fmt.Printf("%s%s%s has a new upstream commit\n", cfg.Colors.Highlight, name, cfg.Colors.Reset)
+						urlPaths[name] = pkg.URLPath
+						outdated = append(outdated, name)
+						staleRevs[name] = stale[name]
+					}
+				}
+			}
+
+			if len(outdated) == 0 {
+				return
+			}
+
+			if upgradeYes {
+				// only record a devel package's revision as "last built"
+				// once it has actually been rebuilt, so declining to
+				// rebuild a checked package doesn't hide it from the next
+				// upgrade --devel
+				onInstalled := func(name string) {
+					if rev, ok := staleRevs[name]; ok {
+						develCache.Revisions[name] = rev
+					}
+				}
+				err := resolveAndBuild(outdated, urlPaths, pacmanArgs, onInstalled)
+				if develCache != nil {
+					if saveErr := develCache.Save(); saveErr != nil {
+						panic(saveErr)
+					}
+				}
 				if err != nil {
 					os.Exit(1)
 				}
 			}
 		},
 	}
+	upgrade.Flags().BoolVarP(&upgradeYes, "noconfirm", "y", false, "install available upgrades without prompting")
+	upgrade.Flags().BoolVar(&upgradeDevel, "devel", false, "also check VCS packages for upstream changes")
+
+	var develCheck = &cobra.Command{
+		Use:   "devel-check [names of packages]",
+		Short: "Check installed VCS packages for upstream changes",
+		Long: `Checks each installed -git, -svn, -hg or -bzr AUR package's PKGBUILD source
+array for its upstream VCS URL, and compares the current upstream revision
+against the one recorded when "upgrade --devel -y" last rebuilt it, since a
+devel package's Version string does not change between rebuilds. This
+command only reports; it records no revisions itself. With no arguments, every
+installed foreign package is considered (non-devel ones are skipped).`,
+		Run: func(cmd *cobra.Command, args []string) {
+			names := args
+			if len(names) == 0 {
+				installed, err := installedForeignPackages(cfg.PacmanBin)
+				if err != nil {
+					panic(err)
+				}
+				for name := range installed {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+			}
+
+			develCache, err := devel.Load(devel.Path())
+			if err != nil {
+				panic(err)
+			}
+
+			// devel-check only reports; it never rebuilds anything, so it
+			// must not touch develCache's last-built revisions, or a
+			// checked-but-not-rebuilt package would never be reported
+			// again once its upstream moves a second time
+			stale := checkDevelPackages(develCache, names)
+
+			staleNames := make([]string, 0, len(stale))
+			for name := range stale {
+				staleNames = append(staleNames, name)
+			}
+			sort.Strings(staleNames)
+
+			for _, name := range staleNames {
+				fmt.Printf("%s%s%s has a new upstream commit\n", cfg.Colors.Highlight, name, cfg.Colors.Reset)
+			}
+		},
+	}
 
 	var version = &cobra.Command{
 		Use:   "version",
@@ -310,7 +832,53 @@ be displayed.`,
 		},
 	}
 
-	var root = &cobra.Command{Use: "seekaur"}
-	root.AddCommand(search, info, tarball, pkgbuild, version)
+	var configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Print the effective configuration",
+		Long: `Prints the configuration seekaur is currently running with, which is the
+config file (see --config) merged over the built-in defaults.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			data, err := json.MarshalIndent(cfg, "", "  ")
+			if err != nil {
+				panic(err)
+			}
+			fmt.Println(string(data))
+		},
+	}
+
+	var configPath string
+	var root = &cobra.Command{
+		Use: "seekaur",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			path := configPath
+			if path == "" {
+				path = config.Path()
+			}
+
+			loaded, err := config.Load(path)
+			if err != nil {
+				return err
+			}
+			cfg = loaded
+
+			aurURL, err = url.Parse(cfg.AURURL)
+			if err != nil {
+				return err
+			}
+
+			httpClient = &http.Client{Timeout: time.Duration(cfg.RequestTimeout) * time.Second}
+			if cfg.CacheTTL > 0 {
+				respCache = cache.New(filepath.Join(config.CacheDir(), "rpc"), time.Duration(cfg.CacheTTL)*time.Second)
+			}
+
+			if !cmd.Flags().Changed("sort") {
+				sortMode = cfg.SortMode
+			}
+			return nil
+		},
+	}
+	root.PersistentFlags().StringVar(&configPath, "config", "", "path to config file (default $XDG_CONFIG_HOME/seekaur/config.json)")
+	root.PersistentFlags().StringVar(&sortMode, "sort", "name", "sort order for package listings: popularity|votes|name")
+	root.AddCommand(search, info, tarball, pkgbuild, depscmd, upgrade, develCheck, configCmd, version)
 	root.Execute()
 }
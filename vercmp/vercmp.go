@@ -0,0 +1,21 @@
+// Package vercmp compares pacman-style version strings.
+package vercmp
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Compare compares two pacman version strings the same way pacman itself
+// would, by shelling out to vercmp(8). It returns a negative number if a
+// is older than b, zero if they are equivalent, and a positive number if
+// a is newer than b.
+func Compare(a, b string) (int, error) {
+	out, err := exec.Command("vercmp", a, b).Output()
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(out)))
+}